@@ -0,0 +1,101 @@
+// Package attestflow implements an ACME-style device attestation flow: a
+// client mints an AIK and opens an Order for it, the server issues a
+// Challenge carrying a credential-activation blob and a quote nonce, and the
+// client Finalizes the order by proving possession of the AIK (by returning
+// the decrypted activation secret, by submitting a WebAuthn-format TPM
+// attestation statement, or by submitting a quote and event log that are
+// evaluated against the server's policy). It is meant to replace ad-hoc RPC wire formats
+// with something a real CA-shaped deployment can reuse.
+package attestflow
+
+import "github.com/google/go-attestation/verifier"
+
+// OrderStatus is the lifecycle state of an Order, mirroring RFC 8555's order
+// state machine.
+type OrderStatus string
+
+const (
+	StatusPending     OrderStatus = "pending"
+	StatusReady       OrderStatus = "ready"
+	StatusProcessing  OrderStatus = "processing"
+	StatusValid       OrderStatus = "valid"
+	StatusInvalid     OrderStatus = "invalid"
+)
+
+// Order tracks a single attestation attempt for one AIK.
+type Order struct {
+	ID string
+
+	// AIKPublic is the AIK's public area, as returned by
+	// attest.AttestationParameters.Public. It is the key an Order is
+	// looked up by.
+	AIKPublic []byte
+
+	// SigningKey is the PKIX, DER-encoded public key of an ordinary
+	// (non-AIK) key the client generates when opening the order. The
+	// client signs subsequent requests against this order (Finalize) with
+	// the matching private key; the AIK itself is never used as a
+	// general-purpose signer, since TPM2_Quote and TPM2_CertifyCreation
+	// produce TPMS_ATTEST signatures, not raw PKCS#1v1.5 signatures over
+	// caller-supplied digests, and so cannot stand in for one.
+	SigningKey []byte
+
+	// TPMVersion is the TPM version the AIK was minted on, needed to
+	// generate the credential-activation Challenge.
+	TPMVersion int
+
+	Status OrderStatus
+
+	// ChallengeID names the Challenge that must be completed to advance
+	// this order out of StatusPending.
+	ChallengeID string
+
+	// Report is the structured result of evaluating the client's event
+	// log and quote against the Server's Policy, set once the order has
+	// finalized with a QuoteAttestation.
+	Report *verifier.AttestationReport
+}
+
+// Challenge carries the credential-activation blob and quote nonce a client
+// must answer to prove possession of the AIK named by its Order.
+type Challenge struct {
+	ID      string
+	OrderID string
+
+	// ActivationBlob is the marshaled attest.EncryptedCredential the
+	// client must decrypt with its AIK and TPM to recover
+	// ActivationSecret.
+	ActivationBlob []byte
+
+	// QuoteNonce is bound into the client's TPM2 quote (and, if the
+	// client finalizes with a WebAuthn statement, into certInfo's
+	// extraData) to prevent replay across sessions.
+	QuoteNonce []byte
+
+	Status OrderStatus
+
+	// activationSecret is the plaintext credential-activation secret the
+	// client must return to prove EK possession. It is never serialized
+	// to clients.
+	activationSecret []byte
+}
+
+// FinalizeRequest is submitted by the client to complete a Challenge.
+// Exactly one of DecryptedSecret, WebAuthnStatement or Attestation should be
+// set: the first two prove possession of the EK/AIK, the last additionally
+// submits a policy-checkable quote and event log.
+type FinalizeRequest struct {
+	DecryptedSecret   []byte            `json:"decryptedSecret,omitempty"`
+	WebAuthnStatement []byte            `json:"webAuthnStatement,omitempty"`
+	Attestation       *QuoteAttestation `json:"attestation,omitempty"`
+}
+
+// QuoteAttestation carries a TPM2 quote and event log for policy
+// evaluation via verifier.VerifyAttestation, rather than trusting
+// client-reported PCR values directly.
+type QuoteAttestation struct {
+	Quote     []byte
+	Signature []byte
+	PCRs      map[uint32][]byte
+	EventLog  []byte
+}
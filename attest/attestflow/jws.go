@@ -0,0 +1,53 @@
+package attestflow
+
+import (
+	"crypto"
+	"fmt"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// SignJWS produces a compact-serialized JWS over payload, signed by signer.
+// kid identifies the signing key to the verifier (an order ID, once an
+// order has established which SigningKey to verify against).
+func SignJWS(signer crypto.Signer, alg jose.SignatureAlgorithm, kid string, payload []byte) ([]byte, error) {
+	key := jose.SigningKey{Algorithm: alg, Key: signer}
+	opts := (&jose.SignerOptions{}).WithHeader("kid", jose.HeaderKey(kid))
+	s, err := jose.NewSigner(key, opts)
+	if err != nil {
+		return nil, fmt.Errorf("attestflow: creating JWS signer: %v", err)
+	}
+
+	obj, err := s.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("attestflow: signing JWS: %v", err)
+	}
+	out, err := obj.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("attestflow: serializing JWS: %v", err)
+	}
+	return []byte(out), nil
+}
+
+// VerifyJWS verifies a compact-serialized JWS against pub and returns its
+// payload. It rejects bodies whose kid header does not match wantKid, so
+// that a request cannot be replayed against an order other than the one it
+// was signed for.
+func VerifyJWS(body []byte, pub crypto.PublicKey, wantKid string) ([]byte, error) {
+	obj, err := jose.ParseSigned(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("attestflow: parsing JWS: %v", err)
+	}
+	if len(obj.Signatures) != 1 {
+		return nil, fmt.Errorf("attestflow: expected exactly one JWS signature, got %d", len(obj.Signatures))
+	}
+	if got := obj.Signatures[0].Header.KeyID; got != wantKid {
+		return nil, fmt.Errorf("attestflow: JWS kid %q does not match expected %q", got, wantKid)
+	}
+
+	payload, err := obj.Verify(pub)
+	if err != nil {
+		return nil, fmt.Errorf("attestflow: verifying JWS signature: %v", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,66 @@
+package attestflow
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func TestSignJWSVerifyJWSRoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	payload := []byte(`{"decryptedSecret":"c2VjcmV0"}`)
+
+	jws, err := SignJWS(key, jose.RS256, "order-1", payload)
+	if err != nil {
+		t.Fatalf("SignJWS() = %v", err)
+	}
+
+	got, err := VerifyJWS(jws, &key.PublicKey, "order-1")
+	if err != nil {
+		t.Fatalf("VerifyJWS() = %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("VerifyJWS() = %q, want %q", got, payload)
+	}
+}
+
+func TestVerifyJWSRejectsKidMismatch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+
+	jws, err := SignJWS(key, jose.RS256, "order-1", []byte("payload"))
+	if err != nil {
+		t.Fatalf("SignJWS() = %v", err)
+	}
+
+	if _, err := VerifyJWS(jws, &key.PublicKey, "order-2"); err == nil {
+		t.Fatal("VerifyJWS() = nil error, want rejection of mismatched kid")
+	}
+}
+
+func TestVerifyJWSRejectsWrongKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() = %v", err)
+	}
+
+	jws, err := SignJWS(key, jose.RS256, "order-1", []byte("payload"))
+	if err != nil {
+		t.Fatalf("SignJWS() = %v", err)
+	}
+
+	if _, err := VerifyJWS(jws, &other.PublicKey, "order-1"); err == nil {
+		t.Fatal("VerifyJWS() = nil error, want rejection with the wrong public key")
+	}
+}
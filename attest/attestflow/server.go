@@ -0,0 +1,270 @@
+package attestflow
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-attestation/verifier"
+
+	tpb "github.com/google/go-attestation/proto"
+)
+
+// Server implements the attestflow HTTP handlers on top of a Store. Mount
+// its handlers under a ServeMux to serve the flow over HTTPS.
+type Server struct {
+	Store Store
+
+	// Roots, if set, is consulted to verify the EK certificate chain of
+	// WebAuthn-format TPM attestation statements submitted to Finalize.
+	// Finalizing with a decrypted activation secret does not require it.
+	Roots *ctx509.CertPool
+
+	// Policy is evaluated against a client's event log and quote when it
+	// finalizes with a QuoteAttestation.
+	Policy verifier.Policy
+}
+
+type newOrderRequest struct {
+	TPMVersion attest.TPMVersion
+	AIK        attest.AttestationParameters
+	EKPem      []byte
+
+	// SigningKey is the PKIX, DER-encoded public key of an ordinary key
+	// the client will use to sign Finalize requests for the resulting
+	// order. See Order.SigningKey.
+	SigningKey []byte
+}
+
+type orderResource struct {
+	ID          string
+	Status      OrderStatus
+	ChallengeID string
+}
+
+type challengeResource struct {
+	ID             string
+	Status         OrderStatus
+	ActivationBlob []byte
+	QuoteNonce     []byte
+}
+
+// NewOrder handles POST requests that open an order for an AIK, returning
+// an orderResource whose ChallengeID names the Challenge the client must
+// complete; the Challenge itself is fetched separately via GetChallenge. The
+// request body is unsigned: JWS-signing it with the AIK itself is not
+// possible before activation, and the AIK is not a general-purpose signing
+// key in any case (TPM2_Quote/TPM2_CertifyCreation sign TPMS_ATTEST
+// structures, not arbitrary payloads). Instead the client supplies an
+// ordinary SigningKey here, and this step is authenticated out-of-band
+// (e.g. mTLS to a provisioning endpoint); subsequent requests on the order
+// are JWS-signed and verified against that SigningKey.
+func (s *Server) NewOrder(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	var req newOrderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+
+	block, _ := pem.Decode(req.EKPem)
+	if block == nil {
+		http.Error(w, "malformed EK", http.StatusBadRequest)
+		return
+	}
+	ek, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		http.Error(w, "malformed EK", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := x509.ParsePKIXPublicKey(req.SigningKey); err != nil {
+		http.Error(w, "malformed signing key", http.StatusBadRequest)
+		return
+	}
+
+	ap := attest.ActivationParameters{TPMVersion: req.TPMVersion, EK: ek, AIK: req.AIK}
+	secret, ec, err := ap.Generate()
+	if err != nil {
+		fmt.Printf("[NewOrder] failed to generate activation challenge: %v\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	ecJSON, err := json.Marshal(ec)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	id := randID()
+	order := &Order{ID: id, AIKPublic: req.AIK.Public, SigningKey: req.SigningKey, TPMVersion: int(req.TPMVersion), Status: StatusPending, ChallengeID: id}
+	challenge := &Challenge{
+		ID:               id,
+		OrderID:          id,
+		ActivationBlob:   ecJSON,
+		activationSecret: secret,
+		QuoteNonce:       randNonce(),
+		Status:           StatusPending,
+	}
+	if err := s.Store.PutOrder(order); err != nil || s.Store.PutChallenge(challenge) != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, orderResource{ID: order.ID, Status: order.Status, ChallengeID: order.ChallengeID})
+}
+
+// GetChallenge returns the current state of the challenge named by the
+// "id" request path element that callers should route to this handler.
+func (s *Server) GetChallenge(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok, err := s.Store.GetChallenge(id)
+	if err != nil || !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, challengeResource{ID: c.ID, Status: c.Status, ActivationBlob: c.ActivationBlob, QuoteNonce: c.QuoteNonce})
+}
+
+// Finalize handles a JWS-signed FinalizeRequest for the order named by id.
+// Proof is accepted as the decrypted activation secret, as a QuoteAttestation
+// whose quote covers the challenge's QuoteNonce and whose event log satisfies
+// Policy, or as a WebAuthn-format TPM attestation statement bound to the
+// challenge's QuoteNonce.
+func (s *Server) Finalize(w http.ResponseWriter, r *http.Request, id string) {
+	order, ok, err := s.Store.GetOrder(id)
+	if err != nil || !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	challenge, ok, err := s.Store.GetChallenge(order.ChallengeID)
+	if err != nil || !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	signingKey, err := x509.ParsePKIXPublicKey(order.SigningKey)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	payload, err := VerifyJWS(body, signingKey, order.ID)
+	if err != nil {
+		fmt.Printf("[Finalize] JWS verification failed for order %s: %v\n", order.ID, err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req FinalizeRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(req.DecryptedSecret) > 0:
+		if !constantTimeEqual(req.DecryptedSecret, challenge.activationSecret) {
+			order.Status, challenge.Status = StatusInvalid, StatusInvalid
+			http.Error(w, "activation failed", http.StatusBadRequest)
+			break
+		}
+		order.Status, challenge.Status = StatusValid, StatusValid
+
+	case req.Attestation != nil:
+		a := req.Attestation
+		report, err := verifier.VerifyAttestation(tpb.TpmVersion(order.TPMVersion), order.AIKPublic, a.Quote, a.Signature, a.PCRs, challenge.QuoteNonce, a.EventLog, s.Policy)
+		if err != nil {
+			fmt.Printf("[Finalize] attestation verification failed for order %s: %v\n", order.ID, err)
+			order.Status, challenge.Status = StatusInvalid, StatusInvalid
+			http.Error(w, "attestation rejected", http.StatusBadRequest)
+			break
+		}
+		order.Report = report
+		if !report.Verified() {
+			order.Status, challenge.Status = StatusInvalid, StatusInvalid
+			http.Error(w, "attestation violates policy", http.StatusBadRequest)
+			break
+		}
+		order.Status, challenge.Status = StatusValid, StatusValid
+
+	case len(req.WebAuthnStatement) > 0:
+		if s.Roots == nil {
+			http.Error(w, "server does not accept WebAuthn statements", http.StatusBadRequest)
+			return
+		}
+		// device-attest-01 has no browser to produce clientDataJSON, so
+		// clientDataHash is simply SHA256(nonce); see the doc comment on
+		// verifier.VerifyWebAuthnTPMStatement.
+		clientDataHash := sha256.Sum256(challenge.QuoteNonce)
+		if _, err := verifier.VerifyWebAuthnTPMStatement(req.WebAuthnStatement, challenge.QuoteNonce, []byte(order.ID), clientDataHash[:], order.AIKPublic, s.Roots); err != nil {
+			fmt.Printf("[Finalize] WebAuthn statement verification failed for order %s: %v\n", order.ID, err)
+			order.Status, challenge.Status = StatusInvalid, StatusInvalid
+			http.Error(w, "attestation statement rejected", http.StatusBadRequest)
+			break
+		}
+		order.Status, challenge.Status = StatusValid, StatusValid
+
+	default:
+		http.Error(w, "no proof supplied", http.StatusBadRequest)
+		return
+	}
+
+	s.Store.PutOrder(order)
+	s.Store.PutChallenge(challenge)
+	writeJSON(w, orderResource{ID: order.ID, Status: order.Status, ChallengeID: order.ChallengeID})
+}
+
+// Handler wires the order/challenge/finalize endpoints onto a ServeMux.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/order", s.NewOrder)
+	mux.HandleFunc("/challenge/", func(w http.ResponseWriter, r *http.Request) {
+		s.GetChallenge(w, r, strings.TrimPrefix(r.URL.Path, "/challenge/"))
+	})
+	mux.HandleFunc("/finalize/", func(w http.ResponseWriter, r *http.Request) {
+		s.Finalize(w, r, strings.TrimPrefix(r.URL.Path, "/finalize/"))
+	})
+	return mux
+}
+
+func randID() string {
+	return fmt.Sprintf("%x", randNonce())
+}
+
+func randNonce() []byte {
+	n := make([]byte, 16)
+	rand.Read(n)
+	return n
+}
+
+func constantTimeEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var v byte
+	for i := range a {
+		v |= a[i] ^ b[i]
+	}
+	return v == 0
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
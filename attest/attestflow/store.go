@@ -0,0 +1,83 @@
+package attestflow
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Store abstracts persistence of Orders and Challenges, so that a Server is
+// not tied to in-memory state.
+type Store interface {
+	// PutOrder creates or updates an order.
+	PutOrder(o *Order) error
+	// GetOrder looks up an order by ID.
+	GetOrder(id string) (*Order, bool, error)
+	// OrderByAIK looks up an order by its AIK public area, used when a
+	// client that already has an AIK re-opens a session.
+	OrderByAIK(aikPublic []byte) (*Order, bool, error)
+
+	// PutChallenge creates or updates a challenge.
+	PutChallenge(c *Challenge) error
+	// GetChallenge looks up a challenge by ID.
+	GetChallenge(id string) (*Challenge, bool, error)
+}
+
+// MemStore is an in-memory Store, suitable for the demo server and for
+// tests. Production deployments should back Store with durable storage.
+type MemStore struct {
+	mu         sync.Mutex
+	orders     map[string]*Order
+	challenges map[string]*Challenge
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{
+		orders:     make(map[string]*Order),
+		challenges: make(map[string]*Challenge),
+	}
+}
+
+func (s *MemStore) PutOrder(o *Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[o.ID] = o
+	return nil
+}
+
+func (s *MemStore) GetOrder(id string) (*Order, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	return o, ok, nil
+}
+
+func (s *MemStore) OrderByAIK(aikPublic []byte) (*Order, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders {
+		if bytes.Equal(o.AIKPublic, aikPublic) {
+			return o, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *MemStore) PutChallenge(c *Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenges[c.ID] = c
+	return nil
+}
+
+func (s *MemStore) GetChallenge(id string) (*Challenge, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.challenges[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return c, true, nil
+}
+
+var _ Store = (*MemStore)(nil)
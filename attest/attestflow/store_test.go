@@ -0,0 +1,57 @@
+package attestflow
+
+import "testing"
+
+func TestMemStoreOrderRoundTrip(t *testing.T) {
+	s := NewMemStore()
+	order := &Order{ID: "order-1", AIKPublic: []byte("aik-pub"), Status: StatusPending}
+
+	if err := s.PutOrder(order); err != nil {
+		t.Fatalf("PutOrder() = %v", err)
+	}
+
+	got, ok, err := s.GetOrder("order-1")
+	if err != nil || !ok {
+		t.Fatalf("GetOrder() = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.ID != order.ID {
+		t.Errorf("GetOrder().ID = %q, want %q", got.ID, order.ID)
+	}
+
+	if _, ok, err := s.GetOrder("missing"); err != nil || ok {
+		t.Fatalf("GetOrder(missing) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	byAIK, ok, err := s.OrderByAIK([]byte("aik-pub"))
+	if err != nil || !ok {
+		t.Fatalf("OrderByAIK() = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if byAIK.ID != order.ID {
+		t.Errorf("OrderByAIK().ID = %q, want %q", byAIK.ID, order.ID)
+	}
+
+	if _, ok, err := s.OrderByAIK([]byte("no-such-aik")); err != nil || ok {
+		t.Fatalf("OrderByAIK(unknown) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+}
+
+func TestMemStoreChallengeRoundTrip(t *testing.T) {
+	s := NewMemStore()
+	challenge := &Challenge{ID: "challenge-1", OrderID: "order-1", Status: StatusPending}
+
+	if err := s.PutChallenge(challenge); err != nil {
+		t.Fatalf("PutChallenge() = %v", err)
+	}
+
+	got, ok, err := s.GetChallenge("challenge-1")
+	if err != nil || !ok {
+		t.Fatalf("GetChallenge() = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.OrderID != challenge.OrderID {
+		t.Errorf("GetChallenge().OrderID = %q, want %q", got.OrderID, challenge.OrderID)
+	}
+
+	if _, ok, err := s.GetChallenge("missing"); err != nil || ok {
+		t.Fatalf("GetChallenge(missing) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+}
@@ -41,8 +41,6 @@ func main() {
 	switch flag.Arg(0) {
 	case "mint-aik":
 		err = runMintAIK(tpm)
-	case "attest":
-		err = runAttest(tpm)
 	case "get-info":
 		err = runGetInfo(tpm)
 	default:
@@ -72,10 +70,9 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, "  mint-aik - Creates an attestation key, storing it in the parameter given by -keyfile.")
 	fmt.Fprintln(os.Stderr, "  get-info - Dumps the parameters of the key given by -keyfile to stdout.")
 	fmt.Fprintln(os.Stderr)
-}
-
-func runAttest(tpm *attest.TPM) error {
-	return nil
+	fmt.Fprintln(os.Stderr, "  There is deliberately no 'attest' command here: printing a WebAuthn-format")
+	fmt.Fprintln(os.Stderr, "  TPM attestation statement bound to a CA nonce requires a fresh TPM2_Certify,")
+	fmt.Fprintln(os.Stderr, "  which attest.AIK does not yet expose. See verifier.VerifyWebAuthnTPMStatement.")
 }
 
 func runGetInfo(tpm *attest.TPM) error {
@@ -2,38 +2,101 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 
 	"github.com/google/certificate-transparency-go/x509"
 	"github.com/google/go-attestation/attest"
+	"github.com/google/go-attestation/attest/attestflow"
 	"github.com/google/go-tpm/tpm2"
+	jose "gopkg.in/square/go-jose.v2"
 )
 
-// runAttest is run when the demo is invoked with sub-command 'attest'.
+type orderResource struct {
+	ID          string
+	Status      string
+	ChallengeID string
+}
+
+type challengeResource struct {
+	ID             string
+	Status         string
+	ActivationBlob []byte
+	QuoteNonce     []byte
+}
+
+// runActivateCredential is run when the demo is invoked with sub-command
+// 'activate-credential'. It opens an order for the AIK at -keyfile, proves
+// EK possession by activating the resulting credential, and finalizes the
+// order with the decrypted secret.
+func runActivateCredential(tpm *attest.TPM) error {
+	aik, err := loadAIK(tpm)
+	if err != nil {
+		return err
+	}
+
+	ek, err := rsaEKPEM(tpm)
+	if err != nil {
+		return err
+	}
+
+	order, signingKey, err := newOrder(tpm, aik, ek)
+	if err != nil {
+		return fmt.Errorf("opening order: %v", err)
+	}
+
+	challenge, err := getChallenge(order.ChallengeID)
+	if err != nil {
+		return fmt.Errorf("fetching challenge: %v", err)
+	}
+
+	var ec attest.EncryptedCredential
+	if err := json.Unmarshal(challenge.ActivationBlob, &ec); err != nil {
+		return fmt.Errorf("malformed activation blob: %v", err)
+	}
+	secret, err := aik.ActivateCredential(tpm, ec)
+	if err != nil {
+		return fmt.Errorf("failed to activate credential: %v", err)
+	}
+
+	_, err = finalize(signingKey, order.ID, attestflow.FinalizeRequest{DecryptedSecret: secret})
+	return err
+}
+
+// runAttest is run when the demo is invoked with sub-command 'attest'. It
+// quotes the TPM's PCRs against the order's challenge nonce, reads the
+// platform's measurement log, and finalizes the order with both so the
+// server can replay the log and evaluate it against policy rather than
+// trusting the self-reported PCR values.
 func runAttest(tpm *attest.TPM) error {
 	aik, err := loadAIK(tpm)
 	if err != nil {
 		return err
 	}
 
-	nonce, err := sendRequest("/get/attest-nonce", requestData{AIK: aik.AttestationParameters()})
+	ek, err := rsaEKPEM(tpm)
 	if err != nil {
 		return err
 	}
+	order, signingKey, err := newOrder(tpm, aik, ek)
+	if err != nil {
+		return fmt.Errorf("opening order: %v", err)
+	}
+	challenge, err := getChallenge(order.ChallengeID)
+	if err != nil {
+		return fmt.Errorf("fetching challenge: %v", err)
+	}
 
 	pcrs, alg, err := tpm.PCRs()
 	if err != nil {
 		return fmt.Errorf("failed to read PCRs: %v", err)
 	}
-	outPCRs := map[uint32][]byte{}
-	for _, p := range pcrs {
-		outPCRs[uint32(p.Index)] = p.Digest
-	}
-
 	var attestAlg attest.HashAlg
 	switch alg {
 	case tpm2.AlgSHA256:
@@ -43,50 +106,117 @@ func runAttest(tpm *attest.TPM) error {
 	default:
 		return fmt.Errorf("unknown tpm2.Algorithm: %v", alg)
 	}
+	outPCRs := map[uint32][]byte{}
+	for _, p := range pcrs {
+		outPCRs[uint32(p.Index)] = p.Digest
+	}
 
-	q, err := aik.Quote(tpm, nonce.Nonce, attestAlg)
+	q, err := aik.Quote(tpm, challenge.QuoteNonce, attestAlg)
 	if err != nil {
 		return fmt.Errorf("failed to generate quote: %v", err)
 	}
 
-	_, err = sendRequest("/do/attest", requestData{
-		TPMVersion: tpm.Version(),
-		AIK:        aik.AttestationParameters(),
-		Quote:      *q,
-		PCRs:       outPCRs,
+	eventLog, err := tpm.MeasurementLog()
+	if err != nil {
+		return fmt.Errorf("failed to read measurement log: %v", err)
+	}
+
+	_, err = finalize(signingKey, order.ID, attestflow.FinalizeRequest{
+		Attestation: &attestflow.QuoteAttestation{
+			Quote:     q.Quote,
+			Signature: q.Signature,
+			PCRs:      outPCRs,
+			EventLog:  eventLog,
+		},
 	})
 	return err
 }
 
-// runActivateCredential is run when the demo is invoked with sub-command
-// 'activate-credential'.
-func runActivateCredential(tpm *attest.TPM) error {
-	aik, err := loadAIK(tpm)
+// newOrder opens an order for aik, generating an ephemeral RSA key the
+// order's Finalize request will be signed with. The AIK itself cannot sign
+// arbitrary payloads (TPM2_Quote/TPM2_CertifyCreation only sign
+// TPMS_ATTEST structures), so possession of the AIK is proven separately,
+// via credential activation or a quote, while this key authenticates the
+// Finalize request.
+func newOrder(tpm *attest.TPM, aik *attest.AIK, ek []byte) (*orderResource, *rsa.PrivateKey, error) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("generating signing key: %v", err)
 	}
-
-	ek, err := rsaEKPEM(tpm)
+	signingKeyDER, err := x509.MarshalPKIXPublicKey(&signingKey.PublicKey)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("marshaling signing key: %v", err)
 	}
 
-	challenge, err := sendRequest("/get/activation-challenge", requestData{
+	body, err := json.Marshal(struct {
+		TPMVersion attest.TPMVersion
+		AIK        attest.AttestationParameters
+		EKPem      []byte
+		SigningKey []byte
+	}{
 		TPMVersion: tpm.Version(),
-		EKPem:      ek,
 		AIK:        aik.AttestationParameters(),
+		EKPem:      ek,
+		SigningKey: signingKeyDER,
 	})
 	if err != nil {
-		return fmt.Errorf("request failed: %v", err)
+		return nil, nil, err
 	}
 
-	secret, err := aik.ActivateCredential(tpm, challenge.ActivationChallenge)
+	resp, err := http.Post("http://"+*addr+"/order", "application/json", bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to activate credential: %v", err)
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("server returned %v", resp.Status)
 	}
 
-	_, err = sendRequest("/do/activation", requestData{DecryptedCredential: secret, AIK: aik.AttestationParameters()})
-	return err
+	var out orderResource
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, nil, err
+	}
+	return &out, signingKey, nil
+}
+
+func getChallenge(id string) (*challengeResource, error) {
+	resp, err := http.Get("http://" + *addr + "/challenge/" + id)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %v", resp.Status)
+	}
+
+	var out challengeResource
+	return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// finalize signs req with signingKey (the ordinary key generated in
+// newOrder, not the AIK) and submits it to /finalize/<orderID>.
+func finalize(signingKey *rsa.PrivateKey, orderID string, req attestflow.FinalizeRequest) (*orderResource, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	jws, err := attestflow.SignJWS(signingKey, jose.RS256, orderID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("signing finalize request: %v", err)
+	}
+
+	resp, err := http.Post("http://"+*addr+"/finalize/"+orderID, "application/jose+json", bytes.NewReader(jws))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %v", resp.Status)
+	}
+
+	var out orderResource
+	return &out, json.NewDecoder(resp.Body).Decode(&out)
 }
 
 func loadAIK(tpm *attest.TPM) (*attest.AIK, error) {
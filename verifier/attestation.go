@@ -0,0 +1,229 @@
+package verifier
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/go-attestation/attest"
+
+	tpb "github.com/google/go-attestation/proto"
+)
+
+// Policy describes the measurements an AttestationReport should be judged
+// against. A nil or zero-valued field disables that check rather than
+// rejecting everything matching it.
+type Policy struct {
+	// RequireSecureBoot, if true, rejects attestations where the parsed
+	// event log reports Secure Boot as disabled.
+	RequireSecureBoot bool
+
+	// AuthorizedKernelCmdlineHashes, AuthorizedShimHashes,
+	// AuthorizedGrubHashes and AuthorizedKernelHashes list the event
+	// digests (the values extended into the PCR, attest.Event.Digest) this
+	// policy accepts for each measurement class. An empty list accepts any
+	// measurement of that class.
+	AuthorizedKernelCmdlineHashes [][]byte
+	AuthorizedShimHashes          [][]byte
+	AuthorizedGrubHashes          [][]byte
+	AuthorizedKernelHashes        [][]byte
+}
+
+// RuleResult records the outcome of evaluating one Policy rule against a
+// replayed event log.
+type RuleResult struct {
+	Rule    string
+	Matched bool
+	Detail  string
+}
+
+// AttestationReport is the structured result of VerifyAttestation: whether
+// the quote is consistent with the replayed event log, and which policy
+// rules matched or were violated.
+type AttestationReport struct {
+	// QuoteVerified records whether the quote's signature is valid and
+	// covers the claimed PCR values.
+	QuoteVerified bool
+	// EventLogVerified records whether replaying the event log
+	// reproduces the claimed PCR values.
+	EventLogVerified bool
+
+	Rules      []RuleResult
+	Violations []RuleResult
+}
+
+// Verified reports whether the attestation satisfied every check: the
+// quote, the event log replay, and every policy rule.
+func (r *AttestationReport) Verified() bool {
+	return r.QuoteVerified && r.EventLogVerified && len(r.Violations) == 0
+}
+
+// VerifyAttestation verifies that quote/sig is a valid TPM2 quote by aik
+// over claimedPCRs, that replaying eventLog (a TCG Canonical Event Log /
+// PC Client Platform Firmware Profile log) reproduces claimedPCRs, and that
+// the replayed measurements satisfy policy. Unlike verifying a raw quote
+// alone, this lets a caller key authorization decisions on measurements
+// (kernel, shim, grub, cmdline) rather than trusting client-reported
+// PCR bytes. IMA measurements are not evaluated: Policy has no IMA rule.
+func VerifyAttestation(tpmVersion tpb.TpmVersion, aikPublic, quote, sig []byte, claimedPCRs map[uint32][]byte, nonce []byte, eventLog []byte, policy Policy) (*AttestationReport, error) {
+	report := &AttestationReport{}
+
+	if _, err := VerifyQuote(tpmVersion, aikPublic, quote, sig, claimedPCRs, nonce); err != nil {
+		return nil, fmt.Errorf("quote verification failed: %v", err)
+	}
+	report.QuoteVerified = true
+
+	pcrs := make([]attest.PCR, 0, len(claimedPCRs))
+	for index, digest := range claimedPCRs {
+		alg := attest.HashSHA256
+		if len(digest) == 20 {
+			alg = attest.HashSHA1
+		}
+		pcrs = append(pcrs, attest.PCR{Index: int(index), Digest: digest, DigestAlg: alg})
+	}
+
+	el, err := attest.ParseEventLog(eventLog)
+	if err != nil {
+		return nil, fmt.Errorf("parsing event log: %v", err)
+	}
+	events, err := el.Verify(pcrs)
+	if err != nil {
+		return nil, fmt.Errorf("event log does not replay to claimed PCR values: %v", err)
+	}
+	report.EventLogVerified = true
+
+	report.Rules = evaluatePolicy(policy, events)
+	for _, r := range report.Rules {
+		if !r.Matched {
+			report.Violations = append(report.Violations, r)
+		}
+	}
+	return report, nil
+}
+
+func evaluatePolicy(policy Policy, events []attest.Event) []RuleResult {
+	var rules []RuleResult
+
+	secureBootSeen, secureBootEnabled := false, false
+	for _, e := range events {
+		if e.Type == attest.EfiVariableDriverConfig && isSecureBootVar(e) {
+			secureBootSeen = true
+			secureBootEnabled = len(e.Data) > 0 && e.Data[len(e.Data)-1] == 1
+		}
+	}
+	if policy.RequireSecureBoot {
+		switch {
+		case !secureBootSeen:
+			rules = append(rules, RuleResult{Rule: "secure_boot", Matched: false, Detail: "no Secure Boot variable measurement found"})
+		case !secureBootEnabled:
+			rules = append(rules, RuleResult{Rule: "secure_boot", Matched: false, Detail: "Secure Boot reported disabled"})
+		default:
+			rules = append(rules, RuleResult{Rule: "secure_boot", Matched: true})
+		}
+	}
+
+	shimEvents, grubEvents := splitShimGrubEvents(events)
+	rules = append(rules, matchDigestRules("kernel_cmdline", policy.AuthorizedKernelCmdlineHashes, events, isKernelCmdlineEvent)...)
+	rules = append(rules, matchDigestRules("shim", policy.AuthorizedShimHashes, shimEvents, isPCR4Event)...)
+	rules = append(rules, matchDigestRules("grub", policy.AuthorizedGrubHashes, grubEvents, isPCR4Event)...)
+	rules = append(rules, matchDigestRules("kernel", policy.AuthorizedKernelHashes, events, isKernelEvent)...)
+
+	return rules
+}
+
+// matchDigestRules evaluates one rule per event selected by match, checking
+// the value actually extended into the PCR (e.Digest, not the event's raw
+// Data) against allowed. An empty allowed list accepts any digest. If
+// allowed is non-empty but no event matches match at all, this reports a
+// single violation rather than silently producing no rules, mirroring how
+// RequireSecureBoot treats an absent measurement as a failure rather than
+// an unconstrained pass.
+func matchDigestRules(name string, allowed [][]byte, events []attest.Event, match func(attest.Event) bool) []RuleResult {
+	var out []RuleResult
+	seen := false
+	for _, e := range events {
+		if !match(e) {
+			continue
+		}
+		seen = true
+		if len(allowed) == 0 {
+			out = append(out, RuleResult{Rule: name, Matched: true, Detail: fmt.Sprintf("pcr %d (unconstrained)", e.Index)})
+			continue
+		}
+		matched := false
+		for _, digest := range allowed {
+			if digestEqual(digest, e.Digest) {
+				matched = true
+				break
+			}
+		}
+		out = append(out, RuleResult{Rule: name, Matched: matched, Detail: fmt.Sprintf("pcr %d", e.Index)})
+	}
+	if !seen && len(allowed) > 0 {
+		out = append(out, RuleResult{Rule: name, Matched: false, Detail: "no measurement found for this class, but the policy requires one"})
+	}
+	return out
+}
+
+func digestEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSecureBootVar reports whether e is the PCR7 measurement of the UEFI
+// "SecureBoot" variable. e.Data is a UEFI_VARIABLE_DATA structure: a 16-byte
+// vendor GUID, the name length (in UTF-16 characters) and data length as
+// little-endian uint64s, then the UTF-16LE name and the variable data.
+func isSecureBootVar(e attest.Event) bool {
+	const name = "SecureBoot"
+	if e.Index != 7 || len(e.Data) < 32+2*len(name) {
+		return false
+	}
+	if binary.LittleEndian.Uint64(e.Data[16:24]) != uint64(len(name)) {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if e.Data[32+2*i] != name[i] || e.Data[33+2*i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isKernelCmdlineEvent(e attest.Event) bool {
+	return e.Index == 8 || e.Index == 12
+}
+
+func isPCR4Event(e attest.Event) bool {
+	return e.Index == 4
+}
+
+// splitShimGrubEvents separates PCR4's measurements into shim's and grub's.
+// Measured boot extends shim into PCR4 first and then grub, both at the same
+// PCR index, so the two can't be told apart by index alone; this uses log
+// order instead, treating the first PCR4 event as shim and every subsequent
+// one as grub. Events are not filtered by event type, so any PCR4 event
+// counts, whether or not it is an EV_EFI_BOOT_SERVICES_APPLICATION.
+func splitShimGrubEvents(events []attest.Event) (shim, grub []attest.Event) {
+	for _, e := range events {
+		if !isPCR4Event(e) {
+			continue
+		}
+		if shim == nil {
+			shim = []attest.Event{e}
+			continue
+		}
+		grub = append(grub, e)
+	}
+	return shim, grub
+}
+
+func isKernelEvent(e attest.Event) bool {
+	return e.Index == 9 || e.Index == 10
+}
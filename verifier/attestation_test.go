@@ -0,0 +1,70 @@
+package verifier
+
+import (
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+)
+
+func TestMatchDigestRulesComparesDigestNotData(t *testing.T) {
+	digest := []byte{0x01, 0x02, 0x03}
+	events := []attest.Event{
+		{Index: 8, Data: []byte("console=ttyS0 root=/dev/sda1"), Digest: digest},
+	}
+
+	rules := matchDigestRules("kernel_cmdline", [][]byte{digest}, events, isKernelCmdlineEvent)
+	if len(rules) != 1 || !rules[0].Matched {
+		t.Fatalf("matchDigestRules() = %+v, want a single matched rule comparing against Digest", rules)
+	}
+}
+
+func TestMatchDigestRulesViolatesOnMissingMeasurement(t *testing.T) {
+	// No events carry an Index matching isPCR4Event, even though the
+	// policy configures AuthorizedShimHashes.
+	events := []attest.Event{
+		{Index: 9, Data: []byte("kernel"), Digest: []byte{0xAA}},
+	}
+
+	rules := matchDigestRules("shim", [][]byte{{0xBB}}, events, isPCR4Event)
+	if len(rules) != 1 || rules[0].Matched {
+		t.Fatalf("matchDigestRules() = %+v, want a single unmatched violation for the absent measurement class", rules)
+	}
+}
+
+func TestMatchDigestRulesUnconstrainedWhenNoAllowList(t *testing.T) {
+	events := []attest.Event{
+		{Index: 4, Data: []byte("shim"), Digest: []byte{0xCC}},
+	}
+
+	rules := matchDigestRules("shim", nil, events, isPCR4Event)
+	if len(rules) != 1 || !rules[0].Matched {
+		t.Fatalf("matchDigestRules() = %+v, want the measurement to pass unconstrained", rules)
+	}
+}
+
+func TestMatchDigestRulesNoOpWhenUnconfigured(t *testing.T) {
+	rules := matchDigestRules("shim", nil, nil, isPCR4Event)
+	if len(rules) != 0 {
+		t.Fatalf("matchDigestRules() = %+v, want no rules when neither an allow-list nor matching events exist", rules)
+	}
+}
+
+func TestSplitShimGrubEventsBothMatchDistinctAllowLists(t *testing.T) {
+	shimDigest := []byte{0x01}
+	grubDigest := []byte{0x02}
+	events := []attest.Event{
+		{Index: 4, Data: []byte("shim"), Digest: shimDigest},
+		{Index: 4, Data: []byte("grub"), Digest: grubDigest},
+	}
+
+	shimEvents, grubEvents := splitShimGrubEvents(events)
+	shimRules := matchDigestRules("shim", [][]byte{shimDigest}, shimEvents, isPCR4Event)
+	grubRules := matchDigestRules("grub", [][]byte{grubDigest}, grubEvents, isPCR4Event)
+
+	if len(shimRules) != 1 || !shimRules[0].Matched {
+		t.Fatalf("shim rules = %+v, want a single matched rule", shimRules)
+	}
+	if len(grubRules) != 1 || !grubRules[0].Matched {
+		t.Fatalf("grub rules = %+v, want a single matched rule", grubRules)
+	}
+}
@@ -0,0 +1,68 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-attestation/verifier/ekroots"
+)
+
+// NewEKVerifierAutofetch returns an EKVerifier whose root and intermediate
+// pools are populated from cache, fetching the known TPM manufacturer EK CA
+// bundles into cache first if they are not already present (or are stale).
+// If opts.FollowAIA is set, verification additionally fetches intermediates
+// named in an EK certificate's Authority Information Access extension that
+// are missing from the pool, caching them for future verifications. A nil
+// cache is replaced by an ekroots.DirCache at its default location.
+func NewEKVerifierAutofetch(ctx context.Context, cache ekroots.Cache, opts ekroots.FetchOptions) (*EKVerifier, error) {
+	if cache == nil {
+		dc, err := ekroots.NewDirCache("")
+		if err != nil {
+			return nil, err
+		}
+		cache = dc
+	}
+	if err := ekroots.Fetch(ctx, cache, opts); err != nil {
+		return nil, err
+	}
+
+	roots, intermediates, err := ekroots.LoadPool(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EKVerifier{
+		roots:         roots,
+		intermediates: intermediates,
+		autofetch:     &autofetchState{cache: cache, opts: opts},
+	}, nil
+}
+
+// Refresh re-fetches the configured manufacturer bundles into v's cache and
+// reloads the root and intermediate pools from it. It is a no-op on an
+// EKVerifier not created via NewEKVerifierAutofetch.
+func (v *EKVerifier) Refresh(ctx context.Context) error {
+	if v.autofetch == nil {
+		return nil
+	}
+	if err := ekroots.Fetch(ctx, v.autofetch.cache, v.autofetch.opts); err != nil {
+		return fmt.Errorf("refreshing EK root pool: %v", err)
+	}
+
+	roots, intermediates, err := ekroots.LoadPool(v.autofetch.cache)
+	if err != nil {
+		return fmt.Errorf("reloading EK root pool: %v", err)
+	}
+	v.mu.Lock()
+	v.roots, v.intermediates = roots, intermediates
+	v.mu.Unlock()
+	return nil
+}
+
+// autofetchState carries the configuration needed to refresh an
+// EKVerifier's pool, and to chase AIA URLs for missing intermediates during
+// verification.
+type autofetchState struct {
+	cache ekroots.Cache
+	opts  ekroots.FetchOptions
+}
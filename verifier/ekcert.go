@@ -1,21 +1,34 @@
 package verifier
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/google/certificate-transparency-go/x509"
 
+	"github.com/google/go-attestation/verifier/ekroots"
 	pb "github.com/google/go-attestation/verifier/proto"
 )
 
 // EKVerifier verifies x509 EK certificates based on a pool of allowed
 // parent certificates.
 type EKVerifier struct {
+	// mu guards roots and intermediates: EKVerifier is intended to be
+	// long-lived and called concurrently (e.g. once per attestflow.Server
+	// request), AIA chasing adds to the intermediates pool in place, and
+	// Refresh replaces both pools.
+	mu                   sync.Mutex
 	roots, intermediates *x509.CertPool
+	opts                 EKVerifierOptions
+
+	// autofetch is set on EKVerifiers created via NewEKVerifierAutofetch,
+	// and enables Refresh and AIA chasing for missing intermediates.
+	autofetch *autofetchState
 }
 
 // VerifyEKCert verifies the properties and provenance of a given EK certificate.
@@ -26,6 +39,7 @@ func (v *EKVerifier) VerifyEKCert(certBytes []byte) (*pb.EkcertVerificationResul
 		return nil, err
 	}
 
+	v.mu.Lock()
 	chains, verificationErr := c.Verify(x509.VerifyOptions{
 		Roots:         v.roots,
 		Intermediates: v.intermediates,
@@ -35,6 +49,17 @@ func (v *EKVerifier) VerifyEKCert(certBytes []byte) (*pb.EkcertVerificationResul
 		// chains as a means to determine provenance.
 		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
 	})
+	if verificationErr != nil && v.autofetch != nil && v.autofetch.opts.FollowAIA {
+		if issuer, fetchErr := ekroots.FetchIssuer(context.Background(), v.autofetch.cache, v.autofetch.opts, c.IssuingCertificateURL); fetchErr == nil && issuer != nil {
+			v.intermediates.AddCert(issuer)
+			chains, verificationErr = c.Verify(x509.VerifyOptions{
+				Roots:         v.roots,
+				Intermediates: v.intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			})
+		}
+	}
+	v.mu.Unlock()
 
 	out := &pb.EkcertVerificationResults{
 		Succeeded:     verificationErr == nil,
@@ -43,12 +68,28 @@ func (v *EKVerifier) VerifyEKCert(certBytes []byte) (*pb.EkcertVerificationResul
 	if verificationErr != nil {
 		out.VerificationError = verificationErr.Error()
 	} else {
-		for _, cert := range chains[0] {
-			out.Chain = append(out.Chain, &pb.EkcertVerificationResults_CertSummary{
+		chain := chains[0]
+		for i, cert := range chain {
+			summary := &pb.EkcertVerificationResults_CertSummary{
 				IssuerCn:  cert.Issuer.CommonName,
 				IssuerOrg: strings.Join(cert.Issuer.Organization, " "),
 				Serial:    cert.SerialNumber.String(),
-			})
+			}
+
+			if v.opts.CheckOCSP || v.opts.CheckCRL {
+				var issuer *x509.Certificate
+				if i+1 < len(chain) {
+					issuer = chain[i+1]
+				}
+				status := checkRevocation(v.opts, cert, issuer)
+				revocationSummary(v.opts, summary, status)
+				if strings.HasPrefix(status, "revoked") || strings.HasPrefix(status, "ocsp_error") || strings.HasPrefix(status, "crl_error") {
+					out.Succeeded = false
+					out.VerificationError = fmt.Sprintf("%s: %s", status, cert.Subject.CommonName)
+				}
+			}
+
+			out.Chain = append(out.Chain, summary)
 		}
 	}
 
@@ -61,6 +102,12 @@ func (v *EKVerifier) VerifyEKCert(certBytes []byte) (*pb.EkcertVerificationResul
 // <XXXX>/RootCA/<cert>.{der,cer,crt)
 // <XXXX>/IntermediateCA/<cert>.{der,cer,crt)
 func NewEKVerifier(certsPath []string) (*EKVerifier, error) {
+	return NewEKVerifierWithOptions(certsPath, EKVerifierOptions{})
+}
+
+// NewEKVerifierWithOptions is like NewEKVerifier, but additionally allows
+// configuring OCSP and CRL based revocation checking via opts.
+func NewEKVerifierWithOptions(certsPath []string, opts EKVerifierOptions) (*EKVerifier, error) {
 	roots := x509.NewCertPool()
 	intermediates := x509.NewCertPool()
 
@@ -82,6 +129,7 @@ func NewEKVerifier(certsPath []string) (*EKVerifier, error) {
 	return &EKVerifier{
 		roots:         roots,
 		intermediates: intermediates,
+		opts:          opts,
 	}, nil
 }
 
@@ -0,0 +1,80 @@
+package ekroots
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Cache persists fetched EK CA bundles between runs. Keys are manufacturer
+// names (see Manufacturers) plus their ".etag" and ".last-modified"
+// validator counterparts; AIA
+// chasing additionally stores fetched intermediates keyed by the issuing
+// certificate's AIA URL.
+type Cache interface {
+	Get(key string) (data []byte, ok bool, err error)
+	Put(key string, data []byte) error
+	Delete(key string) error
+}
+
+// DirCache is a Cache backed by files in a directory, one per key. It is
+// the default Cache used when none is supplied, rooted at an OS-appropriate
+// cache directory.
+type DirCache struct {
+	Dir string
+}
+
+// NewDirCache returns a DirCache rooted at dir, creating it if necessary.
+// If dir is empty, it defaults to a "go-attestation/ekroots" directory
+// under os.UserCacheDir().
+func NewDirCache(dir string) (*DirCache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(base, "go-attestation", "ekroots")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DirCache{Dir: dir}, nil
+}
+
+// path maps key to a cache file name. Keys include both manufacturer names
+// and full AIA issuer URLs; filepath.Base(key) would collide whenever two
+// distinct URLs share a basename (e.g. ".../vendorA/ca.crt" and
+// ".../vendorB/ca.crt"), silently serving one issuer's cached cert in place
+// of another's. Hashing the whole key avoids that regardless of its shape.
+func (c *DirCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements Cache.
+func (c *DirCache) Get(key string) ([]byte, bool, error) {
+	d, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return d, true, nil
+}
+
+// Put implements Cache.
+func (c *DirCache) Put(key string, data []byte) error {
+	return ioutil.WriteFile(c.path(key), data, 0600)
+}
+
+// Delete implements Cache.
+func (c *DirCache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
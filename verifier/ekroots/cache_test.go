@@ -0,0 +1,68 @@
+package ekroots
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDirCacheRoundTrip(t *testing.T) {
+	c, err := NewDirCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirCache() = %v", err)
+	}
+
+	if _, ok, err := c.Get("manufacturer-a"); err != nil || ok {
+		t.Fatalf("Get() on empty cache = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	want := []byte("bundle-bytes")
+	if err := c.Put("manufacturer-a", want); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+	got, ok, err := c.Get("manufacturer-a")
+	if err != nil || !ok {
+		t.Fatalf("Get() after Put = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Get() = %q, want %q", got, want)
+	}
+
+	if err := c.Delete("manufacturer-a"); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if _, ok, err := c.Get("manufacturer-a"); err != nil || ok {
+		t.Fatalf("Get() after Delete = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	if err := c.Delete("never-existed"); err != nil {
+		t.Errorf("Delete() of missing key = %v, want nil", err)
+	}
+}
+
+func TestDirCachePathDoesNotCollideOnSharedBasename(t *testing.T) {
+	c, err := NewDirCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirCache() = %v", err)
+	}
+
+	urlA := "https://vendorA.example.com/certs/ca.crt"
+	urlB := "https://vendorB.example.com/certs/ca.crt"
+	if filepath.Base(urlA) != filepath.Base(urlB) {
+		t.Fatalf("test URLs must share a basename, got %q and %q", filepath.Base(urlA), filepath.Base(urlB))
+	}
+
+	if err := c.Put(urlA, []byte("vendor-a-cert")); err != nil {
+		t.Fatalf("Put(urlA) = %v", err)
+	}
+	if err := c.Put(urlB, []byte("vendor-b-cert")); err != nil {
+		t.Fatalf("Put(urlB) = %v", err)
+	}
+
+	got, ok, err := c.Get(urlA)
+	if err != nil || !ok {
+		t.Fatalf("Get(urlA) = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if string(got) != "vendor-a-cert" {
+		t.Errorf("Get(urlA) = %q, want %q (vendorB's cert would indicate a cache key collision)", got, "vendor-a-cert")
+	}
+}
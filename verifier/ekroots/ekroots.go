@@ -0,0 +1,151 @@
+// Package ekroots maintains a local pool of TPM manufacturer EK root and
+// intermediate CA certificates, fetching and refreshing them from each
+// manufacturer's published bundle so that operators of an attestation
+// service do not need to pre-stage and maintain this pool by hand.
+package ekroots
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// bundles lists the published EK CA bundle for each TPM manufacturer this
+// package knows how to fetch. Each bundle is a DER or PEM blob containing
+// one or more root/intermediate certificates; LoadPool sorts them into the
+// roots or intermediates pool based on whether they are self-signed.
+var bundles = map[string]string{
+	"infineon":  "https://pki.infineon.com/OptigaRsaMfrCA/OptigaRsaMfrCA.crt",
+	"nuvoton":   "https://www.nuvoton.com/security/NTC-TPM-EK-Cert-Chain/NuvotonTPMRootCA.cer",
+	"stmicro":   "https://www.st.com/content/st_com/en/certificates/STM-TPM-EK-Intermediate-CA.crt",
+	"intel-ptt": "https://tsci.intel.com/content/IPS/certificates/IPS_CA.cer",
+	"microsoft": "https://www.microsoft.com/pkiops/certs/MSFT%20TPM%20Root%20Certificate%20Authority%202014.crt",
+	"amd":       "https://ftpm.amd.com/pki/aia/AMD_fTPM_RootCA.crt",
+	"nationz":   "https://www.nationz.com.cn/certs/NationZTPMRootCA.crt",
+}
+
+// Manufacturers returns the list of TPM manufacturers this package can
+// fetch bundles for.
+func Manufacturers() []string {
+	out := make([]string, 0, len(bundles))
+	for m := range bundles {
+		out = append(out, m)
+	}
+	return out
+}
+
+// FetchOptions configures a call to Fetch, Refresh or NewEKVerifierAutofetch.
+type FetchOptions struct {
+	// HTTPClient is used for all bundle and AIA requests. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Manufacturers restricts which vendor bundles are fetched. Nil means
+	// all manufacturers known to this package, from Manufacturers().
+	Manufacturers []string
+
+	// FollowAIA enables fetching missing intermediate certificates named
+	// in an EK certificate's Authority Information Access "CA Issuers"
+	// URL during verification, mirroring how browsers perform AIA
+	// chasing. Requires a Cache to persist fetched intermediates to.
+	FollowAIA bool
+}
+
+func (o FetchOptions) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (o FetchOptions) manufacturers() []string {
+	if o.Manufacturers != nil {
+		return o.Manufacturers
+	}
+	return Manufacturers()
+}
+
+// Fetch downloads the configured manufacturer bundles, storing each under
+// its manufacturer name in cache. Bundles that have not changed since the
+// last Fetch (per ETag/Last-Modified) are skipped.
+//
+// A manufacturer whose bundle can't be fetched does not stop the others
+// from being fetched: vendor CA sites are not uniformly reliable, and one
+// flaky URL should not discard bundles that succeeded for every other
+// manufacturer in the same call (mirroring the soft-fail philosophy
+// EKVerifierOptions.HardFailOCSP/HardFailCRL apply to revocation checking).
+// Fetch only returns an error once every manufacturer in the call has
+// failed; otherwise it returns nil and the caller's cache holds whatever
+// did succeed.
+func Fetch(ctx context.Context, cache Cache, opts FetchOptions) error {
+	manufacturers := opts.manufacturers()
+	var errs []string
+	for _, m := range manufacturers {
+		url, ok := bundles[m]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: unknown manufacturer", m))
+			continue
+		}
+		if err := fetchInto(ctx, cache, opts, m, url); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", m, err))
+		}
+	}
+	if len(errs) == len(manufacturers) && len(errs) > 0 {
+		return fmt.Errorf("ekroots: fetching bundles: all manufacturers failed: %s", strings.Join(errs, "; "))
+	}
+	if len(errs) > 0 {
+		fmt.Printf("[Fetch] some manufacturer bundles failed to fetch, continuing with the rest: %s\n", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// fetchInto downloads url into cache under key, conditional on any
+// previously-stored validator for key.
+func fetchInto(ctx context.Context, cache Cache, opts FetchOptions, key, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if validator, ok, _ := cache.Get(validatorKey(key)); ok {
+		req.Header.Set("If-None-Match", string(validator))
+	}
+	if lastModified, ok, _ := cache.Get(key + ".last-modified"); ok {
+		req.Header.Set("If-Modified-Since", string(lastModified))
+	}
+
+	resp, err := opts.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := cache.Put(key, body); err != nil {
+		return err
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if err := cache.Put(key+".last-modified", []byte(lm)); err != nil {
+			return err
+		}
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return cache.Put(validatorKey(key), []byte(etag))
+	}
+	return nil
+}
+
+func validatorKey(key string) string {
+	return key + ".etag"
+}
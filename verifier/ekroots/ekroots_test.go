@@ -0,0 +1,74 @@
+package ekroots
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// memCache is a minimal in-memory Cache for tests that don't need DirCache's
+// filesystem behavior.
+type memCache struct {
+	data map[string][]byte
+}
+
+func newMemCache() *memCache {
+	return &memCache{data: make(map[string][]byte)}
+}
+
+func (c *memCache) Get(key string) ([]byte, bool, error) {
+	d, ok := c.data[key]
+	return d, ok, nil
+}
+
+func (c *memCache) Put(key string, data []byte) error {
+	c.data[key] = data
+	return nil
+}
+
+func (c *memCache) Delete(key string) error {
+	delete(c.data, key)
+	return nil
+}
+
+// roundTripFunc lets a function satisfy http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestFetchReturnsErrorOnlyWhenEveryManufacturerFails(t *testing.T) {
+	cache := newMemCache()
+	opts := FetchOptions{
+		Manufacturers: []string{"not-a-real-manufacturer", "also-not-real"},
+	}
+
+	if err := Fetch(context.Background(), cache, opts); err == nil {
+		t.Fatal("Fetch() = nil error, want an error when every manufacturer is unknown")
+	}
+}
+
+func TestFetchContinuesPastAFailingManufacturer(t *testing.T) {
+	cache := newMemCache()
+	client := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("bundle-bytes"))),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	opts := FetchOptions{
+		Manufacturers: []string{"not-a-real-manufacturer", "infineon"},
+		HTTPClient:    client,
+	}
+
+	if err := Fetch(context.Background(), cache, opts); err != nil {
+		t.Fatalf("Fetch() = %v, want nil: infineon's bundle should still be fetched despite the other manufacturer failing", err)
+	}
+	if _, ok, _ := cache.Get("infineon"); !ok {
+		t.Error("cache has no entry for infineon after Fetch(), want the successful fetch to have been stored")
+	}
+}
@@ -0,0 +1,122 @@
+package ekroots
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// LoadPool parses every manufacturer bundle present in cache into root and
+// intermediate certificate pools, sorting each certificate by whether it is
+// self-signed.
+func LoadPool(cache Cache) (roots, intermediates *x509.CertPool, err error) {
+	roots = x509.NewCertPool()
+	intermediates = x509.NewCertPool()
+
+	for _, m := range Manufacturers() {
+		data, ok, err := cache.Get(m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ekroots: reading cached %s bundle: %v", m, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := addBundle(data, roots, intermediates); err != nil {
+			return nil, nil, fmt.Errorf("ekroots: parsing cached %s bundle: %v", m, err)
+		}
+	}
+	return roots, intermediates, nil
+}
+
+func addBundle(data []byte, roots, intermediates *x509.CertPool) error {
+	certs, err := parseCertsPossiblyPEM(data)
+	if err != nil {
+		return err
+	}
+	for _, c := range certs {
+		if c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	return nil
+}
+
+func parseCertsPossiblyPEM(data []byte) ([]*x509.Certificate, error) {
+	if certs, err := x509.ParseCertificates(data); err == nil {
+		return certs, nil
+	}
+
+	var (
+		certs []*x509.Certificate
+		rest  = data
+	)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("not a valid DER or PEM certificate bundle")
+	}
+	return certs, nil
+}
+
+// FetchIssuer follows the first CA Issuers URL in aiaURLs (an EK
+// certificate's IssuingCertificateURL field), caching the result under the
+// URL itself so repeat verifications of certs from the same issuer are
+// free. It returns nil if aiaURLs is empty.
+func FetchIssuer(ctx context.Context, cache Cache, opts FetchOptions, aiaURLs []string) (*x509.Certificate, error) {
+	if len(aiaURLs) == 0 {
+		return nil, nil
+	}
+	url := aiaURLs[0]
+
+	if data, ok, err := cache.Get(url); err == nil && ok {
+		certs, err := parseCertsPossiblyPEM(data)
+		if err == nil && len(certs) > 0 {
+			return certs[0], nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := opts.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching issuer %s: unexpected status %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := parseCertsPossiblyPEM(body)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("parsing issuer %s: %v", url, err)
+	}
+	if err := cache.Put(url, body); err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
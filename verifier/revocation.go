@@ -0,0 +1,226 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"golang.org/x/crypto/ocsp"
+
+	pb "github.com/google/go-attestation/verifier/proto"
+)
+
+// CRLCache allows callers to persist fetched CRLs between verifications, so
+// that a fresh CRL is not downloaded for every EK verified. Implementations
+// should honor the CRL's NextUpdate when deciding whether a cached entry is
+// still usable.
+type CRLCache interface {
+	Get(url string) (*pkix.CertificateList, bool)
+	Put(url string, crl *pkix.CertificateList)
+}
+
+// EKVerifierOptions configures revocation checking performed by an
+// EKVerifier. The zero value disables all revocation checking, matching the
+// behavior of NewEKVerifier.
+type EKVerifierOptions struct {
+	// CheckOCSP enables consulting the OCSP responder named in a cert's
+	// Authority Information Access extension, if present.
+	CheckOCSP bool
+	// CheckCRL enables fetching and checking the CRL named in a cert's
+	// CRL Distribution Points extension, if present.
+	CheckCRL bool
+	// HardFailOCSP causes OCSP responder errors (as opposed to an
+	// explicit Revoked response) to fail verification. The zero value
+	// (false) soft-fails responder errors instead, since some vendor OCSP
+	// responders are unreliable; a cert lacking an OCSP URL is always
+	// treated as not_checked, regardless of this setting.
+	HardFailOCSP bool
+	// HardFailCRL mirrors HardFailOCSP for CRL fetch/parse errors.
+	HardFailCRL bool
+
+	// HTTPClient is used to fetch OCSP responses and CRLs. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+	// CRLCache caches fetched CRLs across verifications. Optional.
+	CRLCache CRLCache
+	// Clock returns the current time, used to evaluate CRL freshness and
+	// OCSP response validity. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+func (o EKVerifierOptions) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (o EKVerifierOptions) clock() time.Time {
+	if o.Clock != nil {
+		return o.Clock()
+	}
+	return time.Now()
+}
+
+// checkRevocation consults OCSP and/or CRL for cert, whose issuer is the
+// next certificate up the chain (issuer may be nil if cert is self-signed).
+// It returns a human-readable status suitable for
+// EkcertVerificationResults_CertSummary.RevocationStatus.
+func checkRevocation(opts EKVerifierOptions, cert, issuer *ctx509.Certificate) string {
+	if issuer == nil {
+		return "not_checked: no issuer"
+	}
+
+	var statuses []string
+	if opts.CheckOCSP {
+		switch {
+		case len(cert.OCSPServer) == 0:
+			statuses = append(statuses, "ocsp_not_checked: no responder listed")
+		default:
+			status, err := checkOCSP(opts, cert, issuer)
+			switch {
+			case err != nil && opts.HardFailOCSP:
+				return fmt.Sprintf("ocsp_error: %v", err)
+			case err != nil:
+				statuses = append(statuses, fmt.Sprintf("ocsp_soft_fail: %v", err))
+			case status == "revoked":
+				return "revoked (ocsp)"
+			default:
+				statuses = append(statuses, "ocsp_"+status)
+			}
+		}
+	}
+	if opts.CheckCRL {
+		switch {
+		case len(cert.CRLDistributionPoints) == 0:
+			statuses = append(statuses, "crl_not_checked: no distribution point listed")
+		default:
+			status, err := checkCRL(opts, cert, issuer)
+			switch {
+			case err != nil && opts.HardFailCRL:
+				return fmt.Sprintf("crl_error: %v", err)
+			case err != nil:
+				statuses = append(statuses, fmt.Sprintf("crl_soft_fail: %v", err))
+			case status == "revoked":
+				return "revoked (crl)"
+			default:
+				statuses = append(statuses, "crl_"+status)
+			}
+		}
+	}
+	if len(statuses) == 0 {
+		return "not_checked"
+	}
+	out := statuses[0]
+	for _, s := range statuses[1:] {
+		out += ", " + s
+	}
+	return out
+}
+
+// checkOCSP returns "good" or "revoked" for cert, as reported by the OCSP
+// responder named in its Authority Information Access extension. Callers
+// must ensure cert.OCSPServer is non-empty.
+func checkOCSP(opts EKVerifierOptions, cert, issuer *ctx509.Certificate) (string, error) {
+	stdCert, err := x509.ParseCertificate(cert.Raw)
+	if err != nil {
+		return "", fmt.Errorf("reparsing cert for OCSP: %v", err)
+	}
+	stdIssuer, err := x509.ParseCertificate(issuer.Raw)
+	if err != nil {
+		return "", fmt.Errorf("reparsing issuer for OCSP: %v", err)
+	}
+
+	req, err := ocsp.CreateRequest(stdCert, stdIssuer, nil)
+	if err != nil {
+		return "", fmt.Errorf("building OCSP request: %v", err)
+	}
+
+	resp, err := opts.httpClient().Post(cert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return "", fmt.Errorf("OCSP request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading OCSP response: %v", err)
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(body, stdCert, stdIssuer)
+	if err != nil {
+		return "", fmt.Errorf("parsing OCSP response: %v", err)
+	}
+
+	switch parsed.Status {
+	case ocsp.Good:
+		return "good", nil
+	case ocsp.Revoked:
+		return "revoked", nil
+	default:
+		return "", fmt.Errorf("unknown OCSP status %d", parsed.Status)
+	}
+}
+
+// checkCRL returns "good" or "revoked" for cert, per the CRL named in its
+// CRL Distribution Points extension. Callers must ensure
+// cert.CRLDistributionPoints is non-empty.
+func checkCRL(opts EKVerifierOptions, cert, issuer *ctx509.Certificate) (string, error) {
+	url := cert.CRLDistributionPoints[0]
+
+	crl, err := fetchCRL(opts, url)
+	if err != nil {
+		return "", err
+	}
+
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return "", fmt.Errorf("CRL signature invalid: %v", err)
+	}
+
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return "revoked", nil
+		}
+	}
+	return "good", nil
+}
+
+func fetchCRL(opts EKVerifierOptions, url string) (*pkix.CertificateList, error) {
+	now := opts.clock()
+	if opts.CRLCache != nil {
+		if crl, ok := opts.CRLCache.Get(url); ok && now.Before(crl.TBSCertList.NextUpdate) {
+			return crl, nil
+		}
+	}
+
+	resp, err := opts.httpClient().Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching CRL: %v", err)
+	}
+	defer resp.Body.Close()
+	der, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CRL: %v", err)
+	}
+
+	crl, err := x509.ParseCRL(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CRL: %v", err)
+	}
+
+	if opts.CRLCache != nil {
+		opts.CRLCache.Put(url, crl)
+	}
+	return crl, nil
+}
+
+// revocationSummary attaches a RevocationStatus to a CertSummary, recording
+// when the check was performed.
+func revocationSummary(opts EKVerifierOptions, summary *pb.EkcertVerificationResults_CertSummary, status string) {
+	summary.RevocationStatus = status
+	summary.RevocationCheckedAt = opts.clock().Unix()
+}
@@ -0,0 +1,35 @@
+package verifier
+
+import (
+	"strings"
+	"testing"
+
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+)
+
+func TestCheckRevocationSkipsMissingResponders(t *testing.T) {
+	cert := &ctx509.Certificate{}
+	issuer := &ctx509.Certificate{}
+
+	status := checkRevocation(EKVerifierOptions{CheckOCSP: true, CheckCRL: true}, cert, issuer)
+	if strings.Contains(status, "_error:") {
+		t.Errorf("checkRevocation() = %q, want no hard error for certs lacking OCSP/CRL metadata", status)
+	}
+	if !strings.Contains(status, "ocsp_not_checked") || !strings.Contains(status, "crl_not_checked") {
+		t.Errorf("checkRevocation() = %q, want both checks reported as not_checked", status)
+	}
+}
+
+func TestCheckRevocationNoIssuer(t *testing.T) {
+	cert := &ctx509.Certificate{}
+	if got := checkRevocation(EKVerifierOptions{CheckOCSP: true}, cert, nil); !strings.HasPrefix(got, "not_checked") {
+		t.Errorf("checkRevocation() with nil issuer = %q, want not_checked", got)
+	}
+}
+
+func TestEKVerifierOptionsSoftFailDefault(t *testing.T) {
+	var opts EKVerifierOptions
+	if opts.HardFailOCSP || opts.HardFailCRL {
+		t.Errorf("zero-value EKVerifierOptions should soft-fail by default")
+	}
+}
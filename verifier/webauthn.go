@@ -0,0 +1,165 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/go-tpm/tpm2"
+
+	pb "github.com/google/go-attestation/verifier/proto"
+)
+
+// COSE algorithm identifiers used in WebAuthn "tpm" attestation statements.
+// See https://www.iana.org/assignments/cose/cose.xhtml#algorithms.
+const (
+	coseAlgRS256 = -257
+	coseAlgRS1   = -65535
+)
+
+// webAuthnTPMStatement is the CBOR encoding of a WebAuthn "tpm" attestation
+// statement, as consumed by ACME CAs implementing the device-attest-01
+// challenge. See https://www.w3.org/TR/webauthn-2/#sctn-tpm-attestation.
+type webAuthnTPMStatement struct {
+	Ver      string   `cbor:"ver"`
+	Alg      int64    `cbor:"alg"`
+	X5C      [][]byte `cbor:"x5c"`
+	Sig      []byte   `cbor:"sig"`
+	CertInfo []byte   `cbor:"certInfo"`
+	PubArea  []byte   `cbor:"pubArea"`
+}
+
+// EncodeWebAuthnTPMStatement assembles a WebAuthn-format "tpm" attestation
+// statement from the raw components produced by a TPM2_Certify over an AIK,
+// and CBOR-encodes it for submission to a CA implementing the ACME
+// device-attest-01 challenge.
+//
+// certInfo and sig are the TPMS_ATTEST structure and signature returned by
+// TPM2_Certify over pubArea, with extraData set to SHA256(authData ||
+// clientDataHash). ekCertChain holds the DER-encoded EK certificate chain,
+// EK certificate first.
+func EncodeWebAuthnTPMStatement(alg int64, ekCertChain [][]byte, sig, certInfo, pubArea []byte) ([]byte, error) {
+	stmt := webAuthnTPMStatement{
+		Ver:      "2.0",
+		Alg:      alg,
+		X5C:      ekCertChain,
+		Sig:      sig,
+		CertInfo: certInfo,
+		PubArea:  pubArea,
+	}
+	out, err := cbor.Marshal(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("cbor.Marshal: %v", err)
+	}
+	return out, nil
+}
+
+// VerifyWebAuthnTPMStatement verifies a CBOR-encoded WebAuthn "tpm"
+// attestation statement: that clientDataHash is bound to the CA-issued
+// nonce, that pubArea describes expectedAIKPublic, that sig is a valid
+// TPM2_Certify signature over certInfo from the EK certificate's public
+// key, that certInfo.extraData binds authData and clientDataHash, that
+// certInfo attests to pubArea, and that the EK certificate chains to one
+// of roots.
+//
+// Browser-originated WebAuthn statements set clientDataHash to
+// SHA256(clientDataJSON), which itself embeds the challenge; the
+// no-browser ACME device-attest-01 adaptation this package targets instead
+// sets clientDataHash = SHA256(nonce) directly, so that is what is checked
+// here. Without this check, a statement generated for one challenge could
+// be replayed against any other, defeating the nonce's anti-replay
+// purpose.
+//
+// expectedAIKPublic must be the AIK public area the caller already
+// associates with this order or session (e.g. Order.AIKPublic). Without
+// this check, the statement only proves that some EK chaining to roots
+// certified some AIK — not that it certified the AIK the caller expects —
+// so anyone with a roots-chaining EK cert could finalize another party's
+// order with a statement for their own AIK.
+func VerifyWebAuthnTPMStatement(stmt, nonce, authData, clientDataHash, expectedAIKPublic []byte, roots *x509.CertPool) (*pb.EkcertVerificationResults, error) {
+	wantClientDataHash := sha256.Sum256(nonce)
+	if !bytes.Equal(clientDataHash, wantClientDataHash[:]) {
+		return nil, fmt.Errorf("clientDataHash does not match SHA256(nonce): statement is not bound to this challenge")
+	}
+
+	var s webAuthnTPMStatement
+	if err := cbor.Unmarshal(stmt, &s); err != nil {
+		return nil, fmt.Errorf("cbor.Unmarshal: %v", err)
+	}
+	if s.Ver != "2.0" {
+		return nil, fmt.Errorf("unsupported statement version %q", s.Ver)
+	}
+	if len(s.X5C) == 0 {
+		return nil, fmt.Errorf("x5c chain is empty")
+	}
+	if !bytes.Equal(s.PubArea, expectedAIKPublic) {
+		return nil, fmt.Errorf("pubArea does not match the expected AIK public area")
+	}
+
+	ekCert, err := x509.ParseCertificate(s.X5C[0])
+	if err != nil && x509.IsFatal(err) {
+		return nil, fmt.Errorf("parsing EK certificate: %v", err)
+	}
+
+	pub, err := tpm2.DecodePublic(s.PubArea)
+	if err != nil {
+		return nil, fmt.Errorf("decoding pubArea: %v", err)
+	}
+	attestation, err := tpm2.DecodeAttestationData(s.CertInfo)
+	if err != nil {
+		return nil, fmt.Errorf("decoding certInfo: %v", err)
+	}
+	if attestation.Type != tpm2.TagAttestCertify {
+		return nil, fmt.Errorf("certInfo is not a certify attestation (type %v)", attestation.Type)
+	}
+
+	wantExtraData := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash...))
+	if !bytes.Equal(attestation.ExtraData, wantExtraData[:]) {
+		return nil, fmt.Errorf("certInfo.extraData does not match SHA256(authData || clientDataHash)")
+	}
+
+	nameDigest, err := pub.Name()
+	if err != nil {
+		return nil, fmt.Errorf("computing pubArea name: %v", err)
+	}
+	if !bytes.Equal(nameDigest.Digest.Value, attestation.AttestedCertifyInfo.Name.Digest.Value) {
+		return nil, fmt.Errorf("certInfo.attested.name does not match pubArea")
+	}
+
+	if err := verifyCertifySignature(ekCert, s.Alg, s.CertInfo, s.Sig); err != nil {
+		return nil, fmt.Errorf("verifying certInfo signature: %v", err)
+	}
+
+	ev := &EKVerifier{roots: roots, intermediates: x509.NewCertPool()}
+	for _, raw := range s.X5C[1:] {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil && x509.IsFatal(err) {
+			return nil, fmt.Errorf("parsing intermediate certificate: %v", err)
+		}
+		ev.intermediates.AddCert(c)
+	}
+	return ev.VerifyEKCert(s.X5C[0])
+}
+
+func verifyCertifySignature(ekCert *x509.Certificate, alg int64, certInfo, sig []byte) error {
+	pub, ok := ekCert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported EK public key type %T", ekCert.PublicKey)
+	}
+
+	switch alg {
+	case coseAlgRS256:
+		digest := sha256.Sum256(certInfo)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	case coseAlgRS1:
+		digest := sha1.Sum(certInfo)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA1, digest[:], sig)
+	default:
+		return fmt.Errorf("unsupported COSE algorithm %d", alg)
+	}
+}
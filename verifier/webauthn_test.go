@@ -0,0 +1,60 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestEncodeWebAuthnTPMStatementRoundTrip(t *testing.T) {
+	sig := []byte("sig")
+	certInfo := []byte("certInfo")
+	pubArea := []byte("pubArea")
+	chain := [][]byte{[]byte("ek-cert")}
+
+	out, err := EncodeWebAuthnTPMStatement(coseAlgRS256, chain, sig, certInfo, pubArea)
+	if err != nil {
+		t.Fatalf("EncodeWebAuthnTPMStatement() = %v", err)
+	}
+
+	var s webAuthnTPMStatement
+	if err := cbor.Unmarshal(out, &s); err != nil {
+		t.Fatalf("cbor.Unmarshal() = %v", err)
+	}
+	if s.Ver != "2.0" || s.Alg != coseAlgRS256 || string(s.Sig) != string(sig) ||
+		string(s.CertInfo) != string(certInfo) || string(s.PubArea) != string(pubArea) {
+		t.Errorf("round-tripped statement = %+v, want fields matching input", s)
+	}
+}
+
+func TestVerifyWebAuthnTPMStatementRejectsUnboundClientDataHash(t *testing.T) {
+	nonce := []byte("ca-issued-nonce")
+	wrongHash := sha256.Sum256([]byte("some-other-value"))
+
+	stmt, err := EncodeWebAuthnTPMStatement(coseAlgRS256, [][]byte{[]byte("ek-cert")}, nil, nil, []byte("aik-pub"))
+	if err != nil {
+		t.Fatalf("EncodeWebAuthnTPMStatement() = %v", err)
+	}
+
+	_, err = VerifyWebAuthnTPMStatement(stmt, nonce, []byte("authData"), wrongHash[:], []byte("aik-pub"), nil)
+	if err == nil {
+		t.Fatal("VerifyWebAuthnTPMStatement() = nil error, want rejection of a clientDataHash that doesn't match SHA256(nonce)")
+	}
+}
+
+func TestVerifyWebAuthnTPMStatementRejectsUnexpectedAIK(t *testing.T) {
+	nonce := []byte("ca-issued-nonce")
+	authData := []byte("authData")
+	clientDataHash := sha256.Sum256(nonce)
+
+	stmt, err := EncodeWebAuthnTPMStatement(coseAlgRS256, [][]byte{[]byte("ek-cert")}, nil, nil, []byte("attacker-aik-pub"))
+	if err != nil {
+		t.Fatalf("EncodeWebAuthnTPMStatement() = %v", err)
+	}
+
+	_, err = VerifyWebAuthnTPMStatement(stmt, nonce, authData, clientDataHash[:], []byte("victim-aik-pub"), nil)
+	if err == nil {
+		t.Fatal("VerifyWebAuthnTPMStatement() = nil error, want rejection of a statement certifying a different AIK than expected")
+	}
+}